@@ -0,0 +1,142 @@
+package googledomains
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/libdns/libdns"
+)
+
+// acmeTXTRecordAPIRequest adds or removes ACME challenge TXT records using
+// Google's rotateChallenges endpoint. This endpoint only understands
+// "_acme-challenge" TXT records; anything else must go through the general
+// records API instead (see records.go).
+//
+// rotateChallenges authenticates via a per-domain access token embedded in
+// the request body, not an OAuth2 bearer token, so this path only works
+// with AccessToken; service-account credentials authenticate the general
+// records API (see bearerToken) but can't solve ACME challenges.
+func (p *Provider) acmeTXTRecordAPIRequest(ctx context.Context, zone string, records []libdns.Record, addOrRemove string) ([]libdns.Record, error) {
+	if p.AccessToken == "" {
+		return nil, fmt.Errorf("rotateChallenges requires access_token to be configured; credentials_file/credentials_json only authenticate the general records API")
+	}
+
+	payload, err := p.makeRotateChallengesPayload(zone, records, addOrRemove, p.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.doRequest(ctx, zone, payload)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := handleResponse(resp); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+func (p *Provider) makeRotateChallengesPayload(zone string, records []libdns.Record, addOrRemove, token string) (rotateChallengesBody, error) {
+	if addOrRemove != "add" && addOrRemove != "remove" {
+		return rotateChallengesBody{}, fmt.Errorf("can only add or remove; unrecognized: %s", addOrRemove)
+	}
+
+	payload := rotateChallengesBody{
+		AccessToken:        token,
+		KeepExpiredRecords: p.KeepExpiredRecords,
+	}
+
+	// choose the correct field on the struct to which to append records
+	dest := &payload.RecordsToAdd
+	if addOrRemove == "remove" {
+		dest = &payload.RecordsToRemove
+	}
+
+	// convert incoming record types to the format the API requires
+	for _, rec := range records {
+		if !isACMEChallengeTXT(rec) {
+			return rotateChallengesBody{}, fmt.Errorf("rotateChallenges only supports _acme-challenge TXT records, got %s record named %q", rec.Type, rec.Name)
+		}
+		*dest = append(*dest, acmeTXTRecord{
+			FQDN:   libdns.AbsoluteName(rec.Name, zone),
+			Digest: rec.Value,
+		})
+	}
+
+	return payload, nil
+}
+
+func (p *Provider) doRequest(ctx context.Context, zone string, payload rotateChallengesBody) (*http.Response, error) {
+	uri := fmt.Sprintf("%s%s:rotateChallenges", apiBase, zone)
+
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uri, bytes.NewReader(payloadJSON))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	return p.doWithRetry(req)
+}
+
+func handleResponse(resp *http.Response) error {
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var info errorResponse
+	err := json.NewDecoder(resp.Body).Decode(&info)
+	if err != nil {
+		return fmt.Errorf("reading error body: %v", err)
+	}
+
+	return fmt.Errorf("HTTP %d: %s", resp.StatusCode, info.Error.Message)
+}
+
+type rotateChallengesBody struct {
+	AccessToken        string          `json:"accessToken"`
+	RecordsToAdd       []acmeTXTRecord `json:"recordsToAdd,omitempty"`
+	RecordsToRemove    []acmeTXTRecord `json:"recordsToRemove,omitempty"`
+	KeepExpiredRecords bool            `json:"keepExpiredRecords,omitempty"`
+}
+
+type acmeTXTRecord struct {
+	FQDN       string `json:"fqdn"`
+	Digest     string `json:"digest"`
+	UpdateTime string `json:"updateTime,omitempty"`
+}
+
+type errorResponse struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+		Details []struct {
+			Type            string `json:"@type"`
+			FieldViolations []struct {
+				Description string `json:"description"`
+			} `json:"fieldViolations"`
+		} `json:"details"`
+	} `json:"error"`
+}
+
+// API Reference: https://developers.google.com/domains/acme-dns/reference/rest
+const apiBase = "https://acmedns.googleapis.com/v1/acmeChallengeSets/"
+
+// isACMEChallengeTXT reports whether rec is the kind of record the
+// rotateChallenges endpoint understands: a TXT record at an
+// "_acme-challenge" label.
+func isACMEChallengeTXT(rec libdns.Record) bool {
+	return rec.Type == "TXT" && (rec.Name == "_acme-challenge" || strings.HasPrefix(rec.Name, "_acme-challenge."))
+}
@@ -0,0 +1,29 @@
+package googledomains
+
+import (
+	"testing"
+
+	"github.com/libdns/libdns"
+)
+
+func TestIsACMEChallengeTXT(t *testing.T) {
+	tests := []struct {
+		name string
+		rec  libdns.Record
+		want bool
+	}{
+		{"exact challenge label", libdns.Record{Type: "TXT", Name: "_acme-challenge"}, true},
+		{"subdomain challenge label", libdns.Record{Type: "TXT", Name: "_acme-challenge.staging"}, true},
+		{"non-TXT at challenge label", libdns.Record{Type: "A", Name: "_acme-challenge"}, false},
+		{"TXT at unrelated label", libdns.Record{Type: "TXT", Name: "www"}, false},
+		{"label merely containing the prefix, not matching it", libdns.Record{Type: "TXT", Name: "not_acme-challenge"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isACMEChallengeTXT(tt.rec); got != tt.want {
+				t.Errorf("isACMEChallengeTXT(%+v) = %v, want %v", tt.rec, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package googledomains
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"golang.org/x/oauth2/google"
+)
+
+// oauthScope is the scope service-account credentials are minted for. The
+// rotateChallenges (ACME DNS) endpoint only accepts the static AccessToken,
+// so a minted token is only ever used against the general Cloud DNS records
+// API, which requires a Cloud DNS scope rather than the ACME DNS one.
+const oauthScope = "https://www.googleapis.com/auth/ndev.clouddns.readwrite"
+
+// validateAuth ensures exactly one authentication method is configured, and
+// that a credentials_file/credentials_json config is actually usable.
+//
+// Service-account credentials only authenticate the general Cloud DNS
+// records API (see bearerToken), not rotateChallenges, so they're useless
+// without Project set: Provision would succeed but AppendRecords/
+// DeleteRecords would fail on every ACME challenge and, without Project,
+// every other record too.
+func (p *Provider) validateAuth() error {
+	configured := 0
+	if p.AccessToken != "" {
+		configured++
+	}
+	if p.CredentialsFile != "" {
+		configured++
+	}
+	if p.CredentialsJSON != "" {
+		configured++
+	}
+
+	switch configured {
+	case 0:
+		return fmt.Errorf("exactly one of access_token, credentials_file, or credentials_json must be configured")
+	case 1:
+	default:
+		return fmt.Errorf("only one of access_token, credentials_file, or credentials_json may be configured")
+	}
+
+	if (p.CredentialsFile != "" || p.CredentialsJSON != "") && p.Project == "" {
+		return fmt.Errorf("project is required when using credentials_file/credentials_json: service-account credentials can't authenticate rotateChallenges, so they only work against the general records API")
+	}
+	return nil
+}
+
+// setUpTokenSource builds the oauth2.TokenSource used to mint short-lived
+// bearer tokens from a service account key. It is a no-op when AccessToken
+// is used instead.
+func (p *Provider) setUpTokenSource(ctx context.Context) error {
+	var credsJSON []byte
+	switch {
+	case p.CredentialsFile != "":
+		b, err := os.ReadFile(p.CredentialsFile)
+		if err != nil {
+			return fmt.Errorf("reading credentials_file: %v", err)
+		}
+		credsJSON = b
+	case p.CredentialsJSON != "":
+		credsJSON = []byte(p.CredentialsJSON)
+	default:
+		return nil
+	}
+
+	creds, err := google.CredentialsFromJSON(ctx, credsJSON, oauthScope)
+	if err != nil {
+		return fmt.Errorf("parsing service account credentials: %v", err)
+	}
+
+	p.tokenSource = creds.TokenSource
+	return nil
+}
+
+// bearerToken returns the access token to use for the current request. When
+// service-account credentials are configured, this mints (and caches) a
+// short-lived token, refreshing it automatically once it nears expiry.
+func (p *Provider) bearerToken(ctx context.Context) (string, error) {
+	if p.tokenSource == nil {
+		return p.AccessToken, nil
+	}
+
+	tok, err := p.tokenSource.Token()
+	if err != nil {
+		return "", fmt.Errorf("refreshing service account token: %v", err)
+	}
+	return tok.AccessToken, nil
+}
@@ -0,0 +1,141 @@
+package googledomains
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+)
+
+const (
+	defaultHTTPTimeout = 30 * time.Second
+	defaultMaxRetries  = 4
+	baseRetryBackoff   = 500 * time.Millisecond
+	maxRetryBackoff    = 30 * time.Second
+)
+
+// modulePath identifies this module in its own User-Agent string.
+const modulePath = "github.com/caddy-dns/google-domains"
+
+// httpClient returns the *http.Client to use for API requests, falling back
+// to a client with the default timeout if Provision hasn't run yet (e.g. a
+// Provider constructed directly in tests).
+func (p *Provider) httpClient() *http.Client {
+	if p.client == nil {
+		return &http.Client{Timeout: defaultHTTPTimeout}
+	}
+	return p.client
+}
+
+// retriesAllowed returns how many times a failed request should be retried.
+// A negative MaxRetries disables retries entirely; the zero value (the
+// Caddyfile/JSON default) falls back to defaultMaxRetries.
+func (p *Provider) retriesAllowed() int {
+	switch {
+	case p.MaxRetries < 0:
+		return 0
+	case p.MaxRetries == 0:
+		return defaultMaxRetries
+	default:
+		return p.MaxRetries
+	}
+}
+
+// doWithRetry executes req, retrying on HTTP 429 and 5xx responses with
+// exponential backoff and jitter. It honors a Retry-After header when the
+// server sends one, and aborts immediately if req's context is canceled or
+// its deadline is exceeded.
+func (p *Provider) doWithRetry(req *http.Request) (*http.Response, error) {
+	req.Header.Set("User-Agent", userAgent())
+
+	var bodyBytes []byte
+	if req.Body != nil {
+		var err error
+		bodyBytes, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	client := p.httpClient()
+	maxRetries := p.retriesAllowed()
+
+	for attempt := 0; ; attempt++ {
+		if bodyBytes != nil {
+			req.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+		}
+
+		resp, err := client.Do(req)
+		if err == nil && !shouldRetry(resp.StatusCode) {
+			return resp, nil
+		}
+		if attempt >= maxRetries || req.Context().Err() != nil {
+			return resp, err
+		}
+
+		wait := retryDelay(resp, attempt)
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+func shouldRetry(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
+
+// retryDelay computes how long to wait before the next attempt, preferring
+// the server's Retry-After header (seconds or HTTP-date form) and falling
+// back to exponential backoff with jitter.
+func retryDelay(resp *http.Response, attempt int) time.Duration {
+	if resp != nil {
+		if ra := resp.Header.Get("Retry-After"); ra != "" {
+			if secs, err := strconv.Atoi(ra); err == nil {
+				return time.Duration(secs) * time.Second
+			}
+			if at, err := http.ParseTime(ra); err == nil {
+				if d := time.Until(at); d > 0 {
+					return d
+				}
+			}
+		}
+	}
+
+	backoff := baseRetryBackoff * time.Duration(1<<attempt)
+	if backoff > maxRetryBackoff {
+		backoff = maxRetryBackoff
+	}
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff)/2+1))
+}
+
+// userAgent identifies this module, its version, and the Caddy version it's
+// running under, matching the pattern ACME clients use so Google's API logs
+// can tell providers apart.
+func userAgent() string {
+	version := "unknown"
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range bi.Deps {
+			if dep.Path == modulePath {
+				version = dep.Version
+				break
+			}
+		}
+	}
+
+	caddyVersion, _ := caddy.Version()
+
+	return fmt.Sprintf("%s/%s Caddy/%s", modulePath, version, caddyVersion)
+}
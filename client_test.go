@@ -0,0 +1,83 @@
+package googledomains
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestShouldRetry(t *testing.T) {
+	tests := []struct {
+		statusCode int
+		want       bool
+	}{
+		{http.StatusOK, false},
+		{http.StatusNotFound, false},
+		{http.StatusBadRequest, false},
+		{http.StatusTooManyRequests, true},
+		{http.StatusInternalServerError, true},
+		{http.StatusServiceUnavailable, true},
+	}
+
+	for _, tt := range tests {
+		if got := shouldRetry(tt.statusCode); got != tt.want {
+			t.Errorf("shouldRetry(%d) = %v, want %v", tt.statusCode, got, tt.want)
+		}
+	}
+}
+
+func TestRetryDelayHonorsRetryAfterSeconds(t *testing.T) {
+	resp := &http.Response{Header: http.Header{"Retry-After": []string{"7"}}}
+
+	got := retryDelay(resp, 0)
+	if got != 7*time.Second {
+		t.Errorf("retryDelay() = %v, want %v", got, 7*time.Second)
+	}
+}
+
+func TestRetryDelayHonorsRetryAfterHTTPDate(t *testing.T) {
+	at := time.Now().Add(10 * time.Second)
+	resp := &http.Response{Header: http.Header{"Retry-After": []string{at.UTC().Format(http.TimeFormat)}}}
+
+	got := retryDelay(resp, 0)
+	if got <= 0 || got > 10*time.Second {
+		t.Errorf("retryDelay() = %v, want (0, 10s]", got)
+	}
+}
+
+func TestRetryDelayFallsBackToBackoffWithoutRetryAfter(t *testing.T) {
+	got := retryDelay(nil, 0)
+	if got <= 0 || got > baseRetryBackoff {
+		t.Errorf("retryDelay(nil, 0) = %v, want (0, %v]", got, baseRetryBackoff)
+	}
+}
+
+func TestRetryDelayCapsBackoffGrowth(t *testing.T) {
+	// At a high attempt count, uncapped exponential backoff would be far
+	// larger than maxRetryBackoff; the delay must never exceed it.
+	got := retryDelay(nil, 20)
+	if got > maxRetryBackoff {
+		t.Errorf("retryDelay(nil, 20) = %v, want <= %v", got, maxRetryBackoff)
+	}
+}
+
+func TestRetriesAllowed(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxRetries int
+		want       int
+	}{
+		{"default falls back", 0, defaultMaxRetries},
+		{"negative disables retries", -1, 0},
+		{"explicit value is used as-is", 2, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Provider{MaxRetries: tt.maxRetries}
+			if got := p.retriesAllowed(); got != tt.want {
+				t.Errorf("retriesAllowed() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,16 +1,16 @@
 package googledomains
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
-	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/caddyserver/caddy/v2"
 	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
 	"github.com/caddyserver/certmagic"
 	"github.com/libdns/libdns"
+	"golang.org/x/oauth2"
 )
 
 func init() {
@@ -18,9 +18,43 @@ func init() {
 }
 
 // Provider lets Caddy read and manipulate DNS records hosted by this DNS provider.
+//
+// ACME "_acme-challenge" TXT records are manipulated through Google's
+// ACME DNS (rotateChallenges) API using AccessToken alone. All other record
+// types, along with GetRecords and ListZones, go through the general
+// Cloud DNS records API instead, which additionally requires Project.
 type Provider struct {
-	AccessToken        string `json:"access_token,omitempty"`
+	// AccessToken is a long-lived Google Domains ACME DNS access token.
+	// Exactly one of AccessToken, CredentialsFile, or CredentialsJSON must
+	// be configured.
+	AccessToken string `json:"access_token,omitempty"`
+
+	// CredentialsFile is a path to a Google service account JSON key file,
+	// used to mint short-lived bearer tokens instead of AccessToken.
+	CredentialsFile string `json:"credentials_file,omitempty"`
+
+	// CredentialsJSON is an inline Google service account JSON key, used
+	// to mint short-lived bearer tokens instead of AccessToken.
+	CredentialsJSON string `json:"credentials_json,omitempty"`
+
 	KeepExpiredRecords bool   `json:"keep_expired_records,omitempty"`
+	Project            string `json:"project,omitempty"`
+
+	// HTTPTimeout is the timeout for each HTTP request to Google's APIs.
+	// Default: 30s.
+	HTTPTimeout caddy.Duration `json:"http_timeout,omitempty"`
+
+	// MaxRetries is how many times a request is retried after a 429 or 5xx
+	// response before giving up. Default: 4. A negative value disables
+	// retries.
+	MaxRetries int `json:"max_retries,omitempty"`
+
+	// Propagation, if set, makes AppendRecords wait for ACME challenge TXT
+	// records to become visible on DNS resolvers before returning.
+	Propagation *PropagationConfig `json:"propagation,omitempty"`
+
+	client      *http.Client
+	tokenSource oauth2.TokenSource
 }
 
 // CaddyModule returns the Caddy module information.
@@ -33,148 +67,209 @@ func (Provider) CaddyModule() caddy.ModuleInfo {
 
 // Provision sets up the module. Implements caddy.Provisioner.
 func (p *Provider) Provision(ctx caddy.Context) error {
-	p.AccessToken = caddy.NewReplacer().ReplaceAll(p.AccessToken, "")
-	return nil
-}
+	repl := caddy.NewReplacer()
+	p.AccessToken = repl.ReplaceAll(p.AccessToken, "")
+	p.CredentialsFile = repl.ReplaceAll(p.CredentialsFile, "")
+	p.CredentialsJSON = repl.ReplaceAll(p.CredentialsJSON, "")
 
-// AppendRecords adds records to the zone. It returns the records that were added.
-func (p *Provider) AppendRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
-	return p.acmeTXTRecordAPIRequest(ctx, zone, records, "add")
-}
-
-// DeleteRecords deletes the records from the zone. It returns the records that were deleted.
-func (p *Provider) DeleteRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
-	return p.acmeTXTRecordAPIRequest(ctx, zone, records, "remove")
-}
-
-func (p *Provider) acmeTXTRecordAPIRequest(ctx context.Context, zone string, records []libdns.Record, addOrRemove string) ([]libdns.Record, error) {
-	payload, err := p.makePayload(zone, records, addOrRemove)
-	if err != nil {
-		return nil, err
+	if err := p.validateAuth(); err != nil {
+		return err
 	}
 
-	resp, err := doRequest(ctx, zone, payload)
-	if err != nil {
-		return nil, err
+	timeout := time.Duration(p.HTTPTimeout)
+	if timeout <= 0 {
+		timeout = defaultHTTPTimeout
 	}
-	defer resp.Body.Close()
+	p.client = &http.Client{Timeout: timeout}
 
-	if err := handleResponse(resp); err != nil {
-		return nil, err
+	if err := p.setUpTokenSource(ctx); err != nil {
+		return err
 	}
 
-	return records, nil
+	return nil
 }
 
-func (p *Provider) makePayload(zone string, records []libdns.Record, addOrRemove string) (rotateChallengesBody, error) {
-	if addOrRemove != "add" && addOrRemove != "remove" {
-		return rotateChallengesBody{}, fmt.Errorf("can only add or remove; unrecognized: %s", addOrRemove)
-	}
-
-	// TODO: the Google Domains API is very limited in what kinds of records it supports; return error if unsupported
+// AppendRecords adds records to the zone. It returns the records that were added.
+//
+// "_acme-challenge" TXT records are added via Google's rotateChallenges
+// endpoint; any other record type is added through the general records API,
+// which requires Project to be configured.
+func (p *Provider) AppendRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	acmeRecs, otherRecs := splitACMERecords(records)
 
-	payload := rotateChallengesBody{
-		AccessToken:        p.AccessToken,
-		KeepExpiredRecords: p.KeepExpiredRecords,
-	}
+	var result []libdns.Record
+	if len(acmeRecs) > 0 {
+		added, err := p.acmeTXTRecordAPIRequest(ctx, zone, acmeRecs, "add")
+		if err != nil {
+			return result, err
+		}
+		result = append(result, added...)
 
-	// choose the correct field on the struct to which to append records
-	dest := &payload.RecordsToAdd
-	if addOrRemove == "remove" {
-		dest = &payload.RecordsToRemove
+		if err := p.waitForPropagation(ctx, zone, added); err != nil {
+			return result, err
+		}
 	}
 
-	// convert incoming record types to the format the API requires
-	for _, rec := range records {
-		*dest = append(*dest, acmeTXTRecord{
-			FQDN:   libdns.AbsoluteName(rec.Name, zone),
-			Digest: rec.Value,
-		})
+	if len(otherRecs) > 0 {
+		if err := p.requireProject(); err != nil {
+			return result, err
+		}
+		added, err := p.appendResourceRecordSets(ctx, zone, otherRecs)
+		if err != nil {
+			return result, err
+		}
+		result = append(result, added...)
 	}
 
-	return payload, nil
+	return result, nil
 }
 
-func doRequest(ctx context.Context, zone string, payload rotateChallengesBody) (*http.Response, error) {
-	uri := fmt.Sprintf("%s%s:rotateChallenges", apiBase, zone)
-
-	payloadJSON, err := json.Marshal(payload)
-	if err != nil {
-		return nil, err
-	}
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uri, bytes.NewReader(payloadJSON))
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Content-Type", "application/json")
-
-	return http.DefaultClient.Do(req)
-}
+// DeleteRecords deletes the records from the zone. It returns the records that were deleted.
+//
+// "_acme-challenge" TXT records are removed via Google's rotateChallenges
+// endpoint; any other record type is removed through the general records
+// API, which requires Project to be configured.
+func (p *Provider) DeleteRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	acmeRecs, otherRecs := splitACMERecords(records)
 
-func handleResponse(resp *http.Response) error {
-	if resp.StatusCode == http.StatusOK {
-		return nil
+	var result []libdns.Record
+	if len(acmeRecs) > 0 {
+		removed, err := p.acmeTXTRecordAPIRequest(ctx, zone, acmeRecs, "remove")
+		if err != nil {
+			return result, err
+		}
+		result = append(result, removed...)
 	}
 
-	var info errorResponse
-	err := json.NewDecoder(resp.Body).Decode(&info)
-	if err != nil {
-		return fmt.Errorf("reading error body: %v", err)
+	if len(otherRecs) > 0 {
+		if err := p.requireProject(); err != nil {
+			return result, err
+		}
+		removed, err := p.deleteResourceRecordSets(ctx, zone, otherRecs)
+		if err != nil {
+			return result, err
+		}
+		result = append(result, removed...)
 	}
 
-	return fmt.Errorf("HTTP %d: %s", resp.StatusCode, info.Error.Message)
+	return result, nil
 }
 
 // UnmarshalCaddyfile sets up the DNS provider from Caddyfile tokens. Syntax:
 //
-//	google_domains <access_token>
+//	google_domains [<access_token>] {
+//	    access_token     <token>
+//	    credentials_file <path>
+//	    credentials_json <json>
+//	    project          <gcp_project_id>
+//	    http_timeout     <duration>
+//	    max_retries      <n>
+//	    propagation {
+//	        timeout      <duration>
+//	        interval     <duration>
+//	        resolvers    <resolver>...
+//	        require_all
+//	    }
+//	}
 func (p *Provider) UnmarshalCaddyfile(d *caddyfile.Dispenser) error {
 	for d.Next() {
-		if !d.NextArg() {
-			return d.ArgErr()
-		}
-		p.AccessToken = d.Val()
 		if d.NextArg() {
-			return d.ArgErr()
+			p.AccessToken = d.Val()
+			if d.NextArg() {
+				return d.ArgErr()
+			}
+		}
+		for nesting := d.Nesting(); d.NextBlock(nesting); {
+			switch d.Val() {
+			case "access_token":
+				if !d.NextArg() {
+					return d.ArgErr()
+				}
+				p.AccessToken = d.Val()
+			case "credentials_file":
+				if !d.NextArg() {
+					return d.ArgErr()
+				}
+				p.CredentialsFile = d.Val()
+			case "credentials_json":
+				if !d.NextArg() {
+					return d.ArgErr()
+				}
+				p.CredentialsJSON = d.Val()
+			case "project":
+				if !d.NextArg() {
+					return d.ArgErr()
+				}
+				p.Project = d.Val()
+			case "http_timeout":
+				if !d.NextArg() {
+					return d.ArgErr()
+				}
+				dur, err := caddy.ParseDuration(d.Val())
+				if err != nil {
+					return d.Errf("invalid http_timeout: %v", err)
+				}
+				p.HTTPTimeout = caddy.Duration(dur)
+			case "max_retries":
+				if !d.NextArg() {
+					return d.ArgErr()
+				}
+				n, err := strconv.Atoi(d.Val())
+				if err != nil {
+					return d.Errf("invalid max_retries: %v", err)
+				}
+				p.MaxRetries = n
+			case "propagation":
+				if p.Propagation == nil {
+					p.Propagation = new(PropagationConfig)
+				}
+				for nesting := d.Nesting(); d.NextBlock(nesting); {
+					switch d.Val() {
+					case "timeout":
+						if !d.NextArg() {
+							return d.ArgErr()
+						}
+						dur, err := caddy.ParseDuration(d.Val())
+						if err != nil {
+							return d.Errf("invalid propagation timeout: %v", err)
+						}
+						p.Propagation.Timeout = caddy.Duration(dur)
+					case "interval":
+						if !d.NextArg() {
+							return d.ArgErr()
+						}
+						dur, err := caddy.ParseDuration(d.Val())
+						if err != nil {
+							return d.Errf("invalid propagation interval: %v", err)
+						}
+						p.Propagation.Interval = caddy.Duration(dur)
+					case "resolvers":
+						p.Propagation.Resolvers = d.RemainingArgs()
+						if len(p.Propagation.Resolvers) == 0 {
+							return d.ArgErr()
+						}
+					case "require_all":
+						p.Propagation.RequireAll = true
+					default:
+						return d.ArgErr()
+					}
+				}
+			default:
+				return d.ArgErr()
+			}
 		}
 	}
 	return nil
 }
 
-type rotateChallengesBody struct {
-	AccessToken        string          `json:"accessToken"`
-	RecordsToAdd       []acmeTXTRecord `json:"recordsToAdd,omitempty"`
-	RecordsToRemove    []acmeTXTRecord `json:"recordsToRemove,omitempty"`
-	KeepExpiredRecords bool            `json:"keepExpiredRecords,omitempty"`
-}
-
-type acmeTXTRecord struct {
-	FQDN       string `json:"fqdn"`
-	Digest     string `json:"digest"`
-	UpdateTime string `json:"updateTime,omitempty"`
-}
-
-type errorResponse struct {
-	Error struct {
-		Code    int    `json:"code"`
-		Message string `json:"message"`
-		Status  string `json:"status"`
-		Details []struct {
-			Type            string `json:"@type"`
-			FieldViolations []struct {
-				Description string `json:"description"`
-			} `json:"fieldViolations"`
-		} `json:"details"`
-	} `json:"error"`
-}
-
-// API Reference: https://developers.google.com/domains/acme-dns/reference/rest
-const apiBase = "https://acmedns.googleapis.com/v1/acmeChallengeSets/"
-
 // Interface guards
 var (
 	_ caddyfile.Unmarshaler     = (*Provider)(nil)
 	_ caddy.Provisioner         = (*Provider)(nil)
 	_ certmagic.ACMEDNSProvider = (*Provider)(nil)
+	_ libdns.RecordGetter       = (*Provider)(nil)
+	_ libdns.RecordAppender     = (*Provider)(nil)
+	_ libdns.RecordSetter       = (*Provider)(nil)
+	_ libdns.RecordDeleter      = (*Provider)(nil)
+	_ libdns.ZoneLister         = (*Provider)(nil)
 )
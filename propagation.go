@@ -0,0 +1,221 @@
+package googledomains
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/libdns/libdns"
+)
+
+const (
+	defaultPropagationTimeout  = 2 * time.Minute
+	defaultPropagationInterval = 5 * time.Second
+)
+
+// PropagationConfig enables an opt-in check, run after AppendRecords
+// publishes an ACME challenge, that polls DNS resolvers until the expected
+// TXT value is actually visible before returning control to the caller.
+// This mirrors the Timeout()/propagation-check pattern used by lego's DNS
+// providers, and exists because rotateChallenges returns before the record
+// is visible on authoritative resolvers.
+type PropagationConfig struct {
+	// Timeout is the maximum time to wait for propagation. Default: 2m.
+	Timeout caddy.Duration `json:"timeout,omitempty"`
+
+	// Interval is how often to re-check the resolvers. Default: 5s.
+	Interval caddy.Duration `json:"interval,omitempty"`
+
+	// Resolvers is the list of DNS resolvers (host or host:port) to query.
+	// Defaults to the zone's authoritative nameservers.
+	Resolvers []string `json:"resolvers,omitempty"`
+
+	// RequireAll requires every resolver to observe the record before
+	// returning; by default, any single resolver observing it is enough.
+	RequireAll bool `json:"require_all,omitempty"`
+}
+
+// waitForPropagation blocks until records are visible on the configured (or
+// zone-default) resolvers, or returns an error once ctx is done or the
+// configured Timeout elapses. It is a no-op when Propagation isn't
+// configured.
+func (p *Provider) waitForPropagation(ctx context.Context, zone string, records []libdns.Record) error {
+	cfg := p.Propagation
+	if cfg == nil || len(records) == 0 {
+		return nil
+	}
+
+	// Resolved once, up front: if the zone's authoritative nameservers
+	// change during the poll window below, this check keeps querying the
+	// snapshot taken here rather than picking up the new set.
+	resolvers := cfg.Resolvers
+	if len(resolvers) == 0 {
+		var err error
+		resolvers, err = zoneNameservers(ctx, zone)
+		if err != nil {
+			return fmt.Errorf("propagation: resolving zone nameservers for %q: %v", zone, err)
+		}
+	}
+
+	timeout := time.Duration(cfg.Timeout)
+	if timeout <= 0 {
+		timeout = defaultPropagationTimeout
+	}
+	interval := time.Duration(cfg.Interval)
+	if interval <= 0 {
+		interval = defaultPropagationInterval
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		errs := checkResolvers(ctx, resolvers, zone, records, cfg.RequireAll)
+		if len(errs) == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return &propagationError{zone: zone, errs: errs}
+		case <-time.After(interval):
+		}
+	}
+}
+
+// checkResolvers queries every resolver and returns the set of resolvers
+// that don't yet see records, keyed by resolver. An empty result means the
+// check is satisfied (all resolvers, or at least one, depending on
+// requireAll).
+//
+// A resolver can fail to return a satisfying answer for two very different
+// reasons: it was unreachable or errored (timeout, connection refused,
+// SERVFAIL, ...), which says nothing about whether the record has
+// propagated, or it answered but the record isn't there yet, which is the
+// expected state mid-rollout. checkResolver reports the former as a
+// *lookupError so callers - in particular propagationError's message - can
+// tell a flaky resolver apart from one that's genuinely still behind,
+// rather than lumping both under "didn't see it".
+func checkResolvers(ctx context.Context, resolvers []string, zone string, records []libdns.Record, requireAll bool) map[string]error {
+	errs := map[string]error{}
+	for _, resolver := range resolvers {
+		if err := checkResolver(ctx, resolver, zone, records); err != nil {
+			errs[resolver] = err
+		}
+	}
+
+	if requireAll {
+		return errs
+	}
+	if len(errs) < len(resolvers) {
+		return nil // at least one resolver saw it
+	}
+	return errs
+}
+
+// checkResolver reports whether every record is visible via resolver. The
+// returned error is a *lookupError when resolver could not be queried at
+// all (network/transport failure), and a plain error when it was queried
+// successfully but doesn't yet return the expected value.
+func checkResolver(ctx context.Context, resolver, zone string, records []libdns.Record) error {
+	addr := resolverAddr(resolver)
+	res := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}
+
+	for _, rec := range records {
+		fqdn := libdns.AbsoluteName(rec.Name, zone)
+		got, err := res.LookupTXT(ctx, fqdn)
+		if err != nil {
+			if dnsErr, ok := err.(*net.DNSError); ok && dnsErr.IsNotFound {
+				// NXDOMAIN-type response: resolver answered, it just
+				// doesn't have the record yet. Not a lookup failure.
+				return fmt.Errorf("%s TXT at %s not yet visible: %v", rec.Value, fqdn, err)
+			}
+			return &lookupError{resolver: resolver, fqdn: fqdn, err: err}
+		}
+		if !containsValue(got, rec.Value) {
+			return fmt.Errorf("%s TXT at %s not yet visible", rec.Value, fqdn)
+		}
+	}
+	return nil
+}
+
+// lookupError indicates resolver could not be queried at all, as opposed to
+// answering with a negative result. Timeouts, refused connections, and
+// SERVFAILs land here; they say nothing about whether the record has
+// actually propagated.
+type lookupError struct {
+	resolver, fqdn string
+	err            error
+}
+
+func (e *lookupError) Error() string {
+	return fmt.Sprintf("querying %s via %s: %v", e.fqdn, e.resolver, e.err)
+}
+
+func (e *lookupError) Unwrap() error { return e.err }
+
+func containsValue(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
+
+func resolverAddr(resolver string) string {
+	if _, _, err := net.SplitHostPort(resolver); err == nil {
+		return resolver
+	}
+	return net.JoinHostPort(resolver, "53")
+}
+
+// zoneNameservers looks up the authoritative nameservers for zone, to use
+// as the default set of resolvers to poll.
+func zoneNameservers(ctx context.Context, zone string) ([]string, error) {
+	nss, err := net.DefaultResolver.LookupNS(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+	if len(nss) == 0 {
+		return nil, fmt.Errorf("no NS records found for %q", zone)
+	}
+
+	resolvers := make([]string, len(nss))
+	for i, ns := range nss {
+		resolvers[i] = strings.TrimSuffix(ns.Host, ".")
+	}
+	return resolvers, nil
+}
+
+// propagationError reports, per resolver, why the expected records were
+// not observed before the propagation check timed out.
+type propagationError struct {
+	zone string
+	errs map[string]error
+}
+
+func (e *propagationError) Error() string {
+	resolvers := make([]string, 0, len(e.errs))
+	for resolver := range e.errs {
+		resolvers = append(resolvers, resolver)
+	}
+	sort.Strings(resolvers)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "propagation check for %q timed out:", e.zone)
+	for _, resolver := range resolvers {
+		fmt.Fprintf(&sb, "\n  %s: %v", resolver, e.errs[resolver])
+	}
+	return sb.String()
+}
@@ -0,0 +1,75 @@
+package googledomains
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestContainsValue(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		target string
+		want   bool
+	}{
+		{"present", []string{"a", "b"}, "b", true},
+		{"absent", []string{"a", "b"}, "c", false},
+		{"empty list", nil, "a", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := containsValue(tt.values, tt.target); got != tt.want {
+				t.Errorf("containsValue(%v, %q) = %v, want %v", tt.values, tt.target, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolverAddr(t *testing.T) {
+	tests := []struct {
+		resolver string
+		want     string
+	}{
+		{"8.8.8.8", "8.8.8.8:53"},
+		{"8.8.8.8:5353", "8.8.8.8:5353"},
+		{"ns1.example.com", "ns1.example.com:53"},
+	}
+
+	for _, tt := range tests {
+		if got := resolverAddr(tt.resolver); got != tt.want {
+			t.Errorf("resolverAddr(%q) = %q, want %q", tt.resolver, got, tt.want)
+		}
+	}
+}
+
+func TestLookupErrorUnwrap(t *testing.T) {
+	cause := errors.New("connection refused")
+	err := &lookupError{resolver: "8.8.8.8", fqdn: "_acme-challenge.example.com.", err: cause}
+
+	if !errors.Is(err, cause) {
+		t.Errorf("errors.Is(err, cause) = false, want true")
+	}
+	if !strings.Contains(err.Error(), "connection refused") {
+		t.Errorf("lookupError.Error() = %q, want it to mention the underlying error", err.Error())
+	}
+}
+
+func TestPropagationErrorDistinguishesLookupFailuresFromNotVisible(t *testing.T) {
+	err := &propagationError{
+		zone: "example.com.",
+		errs: map[string]error{
+			"8.8.8.8": &lookupError{resolver: "8.8.8.8", fqdn: "_acme-challenge.example.com.", err: errors.New("i/o timeout")},
+			"1.1.1.1": errors.New("digest TXT at _acme-challenge.example.com. not yet visible"),
+		},
+	}
+
+	msg := err.Error()
+	if !strings.Contains(msg, "i/o timeout") {
+		t.Errorf("propagationError.Error() = %q, want it to surface the lookup failure", msg)
+	}
+	if !strings.Contains(msg, "not yet visible") {
+		t.Errorf("propagationError.Error() = %q, want it to surface the not-visible result", msg)
+	}
+}
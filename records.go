@@ -0,0 +1,467 @@
+package googledomains
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// GetRecords lists all the records in the zone. Implements libdns.RecordGetter.
+func (p *Provider) GetRecords(ctx context.Context, zone string) ([]libdns.Record, error) {
+	if err := p.requireProject(); err != nil {
+		return nil, err
+	}
+
+	managedZone, err := p.findManagedZone(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []libdns.Record
+	pageToken := ""
+	for {
+		page, err := p.listResourceRecordSets(ctx, managedZone, pageToken)
+		if err != nil {
+			return nil, err
+		}
+		for _, rrset := range page.Rrsets {
+			records = append(records, rrset.libdnsRecords(zone)...)
+		}
+		if page.NextPageToken == "" {
+			break
+		}
+		pageToken = page.NextPageToken
+	}
+
+	return records, nil
+}
+
+// SetRecords sets the records in the zone, either by updating existing
+// records or creating new ones. It returns the updated records. Implements
+// libdns.RecordSetter.
+//
+// Google's rotateChallenges endpoint has no notion of "set"; it only knows
+// how to add or remove ACME challenge digests. So any "_acme-challenge" TXT
+// records are instead appended via that endpoint, and everything else is
+// upserted through the general records API.
+func (p *Provider) SetRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	acmeRecs, otherRecs := splitACMERecords(records)
+
+	var result []libdns.Record
+	if len(acmeRecs) > 0 {
+		set, err := p.acmeTXTRecordAPIRequest(ctx, zone, acmeRecs, "add")
+		if err != nil {
+			return result, err
+		}
+		result = append(result, set...)
+	}
+
+	if len(otherRecs) > 0 {
+		if err := p.requireProject(); err != nil {
+			return result, err
+		}
+		set, err := p.setResourceRecordSets(ctx, zone, otherRecs)
+		if err != nil {
+			return result, err
+		}
+		result = append(result, set...)
+	}
+
+	return result, nil
+}
+
+// ListZones returns the list of available DNS zones for the configured
+// project. Implements libdns.ZoneLister.
+func (p *Provider) ListZones(ctx context.Context) ([]libdns.Zone, error) {
+	if err := p.requireProject(); err != nil {
+		return nil, err
+	}
+
+	uri := fmt.Sprintf("%sprojects/%s/managedZones", recordsAPIBase, url.PathEscape(p.Project))
+
+	var zones []libdns.Zone
+	pageToken := ""
+	for {
+		reqURI := uri
+		if pageToken != "" {
+			reqURI += "?" + url.Values{"pageToken": {pageToken}}.Encode()
+		}
+		var page managedZonesListResponse
+		if err := p.doRecordsAPIRequest(ctx, http.MethodGet, reqURI, nil, &page); err != nil {
+			return nil, err
+		}
+		for _, mz := range page.ManagedZones {
+			zones = append(zones, libdns.Zone{Name: mz.DNSName})
+		}
+		if page.NextPageToken == "" {
+			break
+		}
+		pageToken = page.NextPageToken
+	}
+
+	return zones, nil
+}
+
+// splitACMERecords separates records that the ACME rotateChallenges
+// endpoint can handle from everything else that must go through the
+// general records API.
+func splitACMERecords(records []libdns.Record) (acmeRecs, otherRecs []libdns.Record) {
+	for _, rec := range records {
+		if isACMEChallengeTXT(rec) {
+			acmeRecs = append(acmeRecs, rec)
+		} else {
+			otherRecs = append(otherRecs, rec)
+		}
+	}
+	return
+}
+
+func (p *Provider) requireProject() error {
+	if p.Project == "" {
+		return fmt.Errorf("the records API requires \"project\" (GCP project ID) to be configured; only _acme-challenge TXT records are supported without it")
+	}
+	return nil
+}
+
+// findManagedZone resolves a libdns zone (e.g. "example.com.") to the name
+// of the Cloud DNS managed zone that serves it.
+func (p *Provider) findManagedZone(ctx context.Context, zone string) (string, error) {
+	uri := fmt.Sprintf("%sprojects/%s/managedZones?%s", recordsAPIBase, url.PathEscape(p.Project),
+		url.Values{"dnsName": {libdns.AbsoluteName("", zone)}}.Encode())
+
+	var page managedZonesListResponse
+	if err := p.doRecordsAPIRequest(ctx, http.MethodGet, uri, nil, &page); err != nil {
+		return "", err
+	}
+	if len(page.ManagedZones) == 0 {
+		return "", fmt.Errorf("no managed zone found in project %q for zone %q", p.Project, zone)
+	}
+
+	return page.ManagedZones[0].Name, nil
+}
+
+func (p *Provider) listResourceRecordSets(ctx context.Context, managedZone, pageToken string) (rrsetsListResponse, error) {
+	uri := fmt.Sprintf("%sprojects/%s/managedZones/%s/rrsets", recordsAPIBase, url.PathEscape(p.Project), url.PathEscape(managedZone))
+	if pageToken != "" {
+		uri += "?" + url.Values{"pageToken": {pageToken}}.Encode()
+	}
+
+	var page rrsetsListResponse
+	err := p.doRecordsAPIRequest(ctx, http.MethodGet, uri, nil, &page)
+	return page, err
+}
+
+// setResourceRecordSets replaces the record set for each distinct name/type
+// pair present in records with the given values, creating the record set if
+// it doesn't already exist. Used by SetRecords, whose libdns contract is to
+// replace, not merge.
+func (p *Provider) setResourceRecordSets(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	managedZone, err := p.findManagedZone(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := groupRecordsByNameType(zone, records)
+
+	var deletions, additions []resourceRecordSet
+	for _, group := range groups {
+		existing, err := p.findResourceRecordSet(ctx, managedZone, group.Name, group.Type)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			deletions = append(deletions, *existing)
+		}
+		additions = append(additions, group)
+	}
+
+	uri := fmt.Sprintf("%sprojects/%s/managedZones/%s/changes", recordsAPIBase, url.PathEscape(p.Project), url.PathEscape(managedZone))
+	body := change{Additions: additions, Deletions: deletions}
+
+	var result change
+	if err := p.doRecordsAPIRequest(ctx, http.MethodPost, uri, body, &result); err != nil {
+		return nil, err
+	}
+
+	var set []libdns.Record
+	for _, rrset := range result.Additions {
+		set = append(set, rrset.libdnsRecords(zone)...)
+	}
+	return set, nil
+}
+
+// appendResourceRecordSets adds records to the zone without disturbing
+// existing values at the same name/type: for each distinct name/type pair,
+// any existing record set is merged with the new values rather than
+// replaced. Used by AppendRecords, whose libdns contract is to add, not
+// replace.
+func (p *Provider) appendResourceRecordSets(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	managedZone, err := p.findManagedZone(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := groupRecordsByNameType(zone, records)
+
+	var deletions, additions []resourceRecordSet
+	for _, group := range groups {
+		existing, err := p.findResourceRecordSet(ctx, managedZone, group.Name, group.Type)
+		if err != nil {
+			return nil, err
+		}
+		if existing == nil {
+			additions = append(additions, group)
+			continue
+		}
+		deletions = append(deletions, *existing)
+		additions = append(additions, resourceRecordSet{
+			Name:    existing.Name,
+			Type:    existing.Type,
+			TTL:     group.TTL,
+			Rrdatas: mergeRrdatas(existing.Rrdatas, group.Rrdatas),
+		})
+	}
+
+	uri := fmt.Sprintf("%sprojects/%s/managedZones/%s/changes", recordsAPIBase, url.PathEscape(p.Project), url.PathEscape(managedZone))
+	body := change{Additions: additions, Deletions: deletions}
+	if err := p.doRecordsAPIRequest(ctx, http.MethodPost, uri, body, nil); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// mergeRrdatas returns existing with any values from toAdd appended,
+// skipping values already present so repeated appends don't duplicate data.
+func mergeRrdatas(existing, toAdd []string) []string {
+	have := make(map[string]bool, len(existing))
+	for _, v := range existing {
+		have[v] = true
+	}
+
+	merged := append([]string{}, existing...)
+	for _, v := range toAdd {
+		if !have[v] {
+			merged = append(merged, v)
+			have[v] = true
+		}
+	}
+	return merged
+}
+
+// deleteResourceRecordSets removes the given values from the matching
+// record sets, deleting the record set entirely once its last value is
+// removed.
+func (p *Provider) deleteResourceRecordSets(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	managedZone, err := p.findManagedZone(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := groupRecordsByNameType(zone, records)
+
+	var deletions, additions []resourceRecordSet
+	var removed []libdns.Record
+	for _, group := range groups {
+		existing, err := p.findResourceRecordSet(ctx, managedZone, group.Name, group.Type)
+		if err != nil {
+			return nil, err
+		}
+		if existing == nil {
+			continue
+		}
+
+		remaining := subtractRrdatas(existing.Rrdatas, group.Rrdatas)
+		present := subtractRrdatas(existing.Rrdatas, remaining)
+		if len(present) == 0 {
+			continue
+		}
+		deletions = append(deletions, *existing)
+		removed = append(removed, resourceRecordSet{Name: existing.Name, Type: existing.Type, TTL: existing.TTL, Rrdatas: present}.libdnsRecords(zone)...)
+
+		if len(remaining) > 0 {
+			additions = append(additions, resourceRecordSet{Name: existing.Name, Type: existing.Type, TTL: existing.TTL, Rrdatas: remaining})
+		}
+	}
+
+	if len(deletions) == 0 {
+		return nil, nil
+	}
+
+	uri := fmt.Sprintf("%sprojects/%s/managedZones/%s/changes", recordsAPIBase, url.PathEscape(p.Project), url.PathEscape(managedZone))
+	body := change{Additions: additions, Deletions: deletions}
+	if err := p.doRecordsAPIRequest(ctx, http.MethodPost, uri, body, nil); err != nil {
+		return nil, err
+	}
+
+	return removed, nil
+}
+
+func subtractRrdatas(existing, toRemove []string) []string {
+	remove := make(map[string]bool, len(toRemove))
+	for _, v := range toRemove {
+		remove[v] = true
+	}
+
+	var remaining []string
+	for _, v := range existing {
+		if !remove[v] {
+			remaining = append(remaining, v)
+		}
+	}
+	return remaining
+}
+
+func (p *Provider) findResourceRecordSet(ctx context.Context, managedZone, name, recordType string) (*resourceRecordSet, error) {
+	uri := fmt.Sprintf("%sprojects/%s/managedZones/%s/rrsets/%s/%s", recordsAPIBase,
+		url.PathEscape(p.Project), url.PathEscape(managedZone), url.PathEscape(name), url.PathEscape(recordType))
+
+	var rrset resourceRecordSet
+	err := p.doRecordsAPIRequest(ctx, http.MethodGet, uri, nil, &rrset)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &rrset, nil
+}
+
+// groupRecordsByNameType merges records that share a name and type into a
+// single resourceRecordSet, since that's the granularity Cloud DNS updates
+// at.
+func groupRecordsByNameType(zone string, records []libdns.Record) []resourceRecordSet {
+	var order []string
+	byKey := map[string]*resourceRecordSet{}
+
+	for _, rec := range records {
+		fqdn := libdns.AbsoluteName(rec.Name, zone)
+		key := fqdn + "/" + rec.Type
+		rrset, ok := byKey[key]
+		if !ok {
+			rrset = &resourceRecordSet{Name: fqdn, Type: rec.Type, TTL: int64(rec.TTL.Seconds())}
+			byKey[key] = rrset
+			order = append(order, key)
+		}
+		rrset.Rrdatas = append(rrset.Rrdatas, rec.Value)
+	}
+
+	groups := make([]resourceRecordSet, 0, len(order))
+	for _, key := range order {
+		groups = append(groups, *byKey[key])
+	}
+	return groups
+}
+
+func (p *Provider) doRecordsAPIRequest(ctx context.Context, method, uri string, body any, out any) error {
+	var bodyReader io.Reader
+	if body != nil {
+		payloadJSON, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		bodyReader = bytes.NewReader(payloadJSON)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, uri, bodyReader)
+	if err != nil {
+		return err
+	}
+
+	token, err := p.bearerToken(ctx)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := p.doWithRetry(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return &recordsAPIError{statusCode: resp.StatusCode, message: "not found"}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var info errorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+			return fmt.Errorf("reading error body: %v", err)
+		}
+		return &recordsAPIError{statusCode: resp.StatusCode, message: info.Error.Message}
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+type recordsAPIError struct {
+	statusCode int
+	message    string
+}
+
+func (e *recordsAPIError) Error() string {
+	return fmt.Sprintf("HTTP %d: %s", e.statusCode, e.message)
+}
+
+func isNotFound(err error) bool {
+	apiErr, ok := err.(*recordsAPIError)
+	return ok && apiErr.statusCode == http.StatusNotFound
+}
+
+// resourceRecordSet mirrors a Cloud DNS ResourceRecordSet.
+type resourceRecordSet struct {
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	TTL     int64    `json:"ttl"`
+	Rrdatas []string `json:"rrdatas"`
+}
+
+// libdnsRecords converts rrset to libdns.Records with Name relative to zone,
+// as the libdns contract requires; rrset.Name itself is always the absolute
+// FQDN Cloud DNS returns.
+func (rrset resourceRecordSet) libdnsRecords(zone string) []libdns.Record {
+	records := make([]libdns.Record, 0, len(rrset.Rrdatas))
+	for _, data := range rrset.Rrdatas {
+		records = append(records, libdns.Record{
+			Type:  rrset.Type,
+			Name:  libdns.RelativeName(rrset.Name, zone),
+			Value: data,
+			TTL:   time.Duration(rrset.TTL) * time.Second,
+		})
+	}
+	return records
+}
+
+type change struct {
+	Additions []resourceRecordSet `json:"additions,omitempty"`
+	Deletions []resourceRecordSet `json:"deletions,omitempty"`
+}
+
+type managedZone struct {
+	Name    string `json:"name"`
+	DNSName string `json:"dnsName"`
+}
+
+type managedZonesListResponse struct {
+	ManagedZones  []managedZone `json:"managedZones"`
+	NextPageToken string        `json:"nextPageToken"`
+}
+
+type rrsetsListResponse struct {
+	Rrsets        []resourceRecordSet `json:"rrsets"`
+	NextPageToken string              `json:"nextPageToken"`
+}
+
+// API Reference: https://cloud.google.com/dns/docs/reference/v1
+const recordsAPIBase = "https://dns.googleapis.com/dns/v1/"
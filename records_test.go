@@ -0,0 +1,151 @@
+package googledomains
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func TestGroupRecordsByNameType(t *testing.T) {
+	records := []libdns.Record{
+		{Type: "A", Name: "www", Value: "1.1.1.1", TTL: 300 * time.Second},
+		{Type: "A", Name: "www", Value: "2.2.2.2", TTL: 300 * time.Second},
+		{Type: "TXT", Name: "www", Value: "hello"},
+		{Type: "A", Name: "@", Value: "3.3.3.3"},
+	}
+
+	got := groupRecordsByNameType("example.com.", records)
+
+	want := []resourceRecordSet{
+		{Name: "www.example.com.", Type: "A", TTL: 300, Rrdatas: []string{"1.1.1.1", "2.2.2.2"}},
+		{Name: "www.example.com.", Type: "TXT", TTL: 0, Rrdatas: []string{"hello"}},
+		{Name: "example.com.", Type: "A", TTL: 0, Rrdatas: []string{"3.3.3.3"}},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("groupRecordsByNameType() = %+v, want %+v", got, want)
+	}
+}
+
+func TestMergeRrdatas(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []string
+		toAdd    []string
+		want     []string
+	}{
+		{
+			name:     "disjoint values appended",
+			existing: []string{"1.1.1.1"},
+			toAdd:    []string{"2.2.2.2"},
+			want:     []string{"1.1.1.1", "2.2.2.2"},
+		},
+		{
+			name:     "duplicate values not repeated",
+			existing: []string{"1.1.1.1", "2.2.2.2"},
+			toAdd:    []string{"2.2.2.2", "3.3.3.3"},
+			want:     []string{"1.1.1.1", "2.2.2.2", "3.3.3.3"},
+		},
+		{
+			name:     "nothing to add",
+			existing: []string{"1.1.1.1"},
+			toAdd:    nil,
+			want:     []string{"1.1.1.1"},
+		},
+		{
+			name:     "nothing existing",
+			existing: nil,
+			toAdd:    []string{"1.1.1.1"},
+			want:     []string{"1.1.1.1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergeRrdatas(tt.existing, tt.toAdd)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeRrdatas(%v, %v) = %v, want %v", tt.existing, tt.toAdd, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubtractRrdatas(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []string
+		toRemove []string
+		want     []string
+	}{
+		{
+			name:     "removes matching values",
+			existing: []string{"1.1.1.1", "2.2.2.2", "3.3.3.3"},
+			toRemove: []string{"2.2.2.2"},
+			want:     []string{"1.1.1.1", "3.3.3.3"},
+		},
+		{
+			name:     "removing everything leaves nil",
+			existing: []string{"1.1.1.1"},
+			toRemove: []string{"1.1.1.1"},
+			want:     nil,
+		},
+		{
+			name:     "removing nothing present is a no-op",
+			existing: []string{"1.1.1.1"},
+			toRemove: []string{"9.9.9.9"},
+			want:     []string{"1.1.1.1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := subtractRrdatas(tt.existing, tt.toRemove)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("subtractRrdatas(%v, %v) = %v, want %v", tt.existing, tt.toRemove, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResourceRecordSetLibdnsRecordsReturnsZoneRelativeNames(t *testing.T) {
+	rrset := resourceRecordSet{Name: "www.example.com.", Type: "A", TTL: 300, Rrdatas: []string{"1.1.1.1", "2.2.2.2"}}
+
+	got := rrset.libdnsRecords("example.com.")
+
+	want := []libdns.Record{
+		{Type: "A", Name: "www", Value: "1.1.1.1", TTL: 300 * time.Second},
+		{Type: "A", Name: "www", Value: "2.2.2.2", TTL: 300 * time.Second},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("libdnsRecords() = %+v, want %+v", got, want)
+	}
+}
+
+func TestResourceRecordSetLibdnsRecordsAtZoneApex(t *testing.T) {
+	rrset := resourceRecordSet{Name: "example.com.", Type: "A", Rrdatas: []string{"1.1.1.1"}}
+
+	got := rrset.libdnsRecords("example.com.")
+
+	if len(got) != 1 || got[0].Name != "" {
+		t.Errorf("libdnsRecords() = %+v, want Name %q", got, "")
+	}
+}
+
+func TestSplitACMERecords(t *testing.T) {
+	records := []libdns.Record{
+		{Type: "TXT", Name: "_acme-challenge", Value: "digest"},
+		{Type: "A", Name: "www", Value: "1.1.1.1"},
+		{Type: "TXT", Name: "_acme-challenge.staging", Value: "digest2"},
+	}
+
+	acme, other := splitACMERecords(records)
+
+	if len(acme) != 2 || len(other) != 1 {
+		t.Fatalf("splitACMERecords() = %d acme, %d other; want 2, 1", len(acme), len(other))
+	}
+	if other[0].Name != "www" {
+		t.Errorf("other[0].Name = %q, want %q", other[0].Name, "www")
+	}
+}